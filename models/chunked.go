@@ -0,0 +1,185 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+const (
+	defaultMaxParallelChunks = 4
+	defaultChunkSize         = 8 << 20 // 8MB
+)
+
+// chunkProgress is persisted next to the in-progress download so an
+// interrupted fetch can resume instead of restarting from scratch.
+type chunkProgress struct {
+	URL       string `json:"url"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk_size"`
+	Done      []bool `json:"done"`
+}
+
+func loadChunkProgress(path string) chunkProgress {
+	f, err := os.Open(path)
+	if err != nil {
+		return chunkProgress{}
+	}
+	defer f.Close()
+	var progress chunkProgress
+	if err := json.NewDecoder(f).Decode(&progress); err != nil {
+		return chunkProgress{}
+	}
+	return progress
+}
+
+func saveChunkProgress(path string, progress chunkProgress) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(progress)
+}
+
+// fetchChunked downloads size bytes from f.url into dest using concurrent
+// Range requests, resuming from a matching chunkProgress file if one exists.
+func (f *httpFetcher) fetchChunked(ctx context.Context, dest string, size int64) error {
+	chunkSize := f.chunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	numChunks := int((size + chunkSize - 1) / chunkSize)
+
+	partPath := dest + ".part"
+	progressPath := dest + ".progress.json"
+
+	progress := loadChunkProgress(progressPath)
+	if progress.URL != f.url || progress.Size != size || progress.ChunkSize != chunkSize || len(progress.Done) != numChunks {
+		progress = chunkProgress{URL: f.url, Size: size, ChunkSize: chunkSize, Done: make([]bool, numChunks)}
+		if err := allocateSparseFile(partPath, size); err != nil {
+			return fmt.Errorf("could not allocate %s: %w", partPath, err)
+		}
+	} else {
+		remaining := 0
+		for _, done := range progress.Done {
+			if !done {
+				remaining++
+			}
+		}
+		f.logger.Infof("resuming download of %s: %d/%d chunks remaining", f.url, remaining, numChunks)
+	}
+
+	file, err := os.OpenFile(partPath, os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", partPath, err)
+	}
+
+	maxParallel := f.maxParallelChunks
+	if maxParallel <= 0 {
+		maxParallel = defaultMaxParallelChunks
+	}
+
+	sem := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errs := make([]error, numChunks)
+
+	for i := 0; i < numChunks; i++ {
+		if progress.Done[i] {
+			continue
+		}
+		i := i
+		start := int64(i) * chunkSize
+		end := start + chunkSize - 1
+		if end > size-1 {
+			end = size - 1
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := f.downloadRange(ctx, file, start, end); err != nil {
+				errs[i] = err
+				return
+			}
+			mu.Lock()
+			progress.Done[i] = true
+			if err := saveChunkProgress(progressPath, progress); err != nil {
+				f.logger.Errorf("could not persist download progress for %s: %v", f.url, err)
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not finish writing %s: %w", partPath, err)
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	os.Remove(progressPath)
+	if err := os.Rename(partPath, dest); err != nil {
+		return fmt.Errorf("could not finalize %s: %w", dest, err)
+	}
+	return nil
+}
+
+// downloadRange fetches [start, end] (inclusive) of f.url and writes it into
+// file at offset start.
+func (f *httpFetcher) downloadRange(ctx context.Context, file *os.File, start, end int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return fmt.Errorf("could not build range request for %s: %w", f.url, err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not fetch bytes %d-%d of %s: %w", start, end, f.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not fetch bytes %d-%d of %s: status %s", start, end, f.url, resp.Status)
+	}
+
+	offset := start
+	buf := make([]byte, 256*1024)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, writeErr := file.WriteAt(buf[:n], offset); writeErr != nil {
+				return fmt.Errorf("could not write bytes %d-%d of %s: %w", start, end, f.url, writeErr)
+			}
+			offset += int64(n)
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return fmt.Errorf("could not read bytes %d-%d of %s: %w", start, end, f.url, readErr)
+		}
+	}
+}
+
+// allocateSparseFile creates path and sizes it to size bytes without writing
+// any data, so concurrent chunk writers can WriteAt their own offsets.
+func allocateSparseFile(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Truncate(size)
+}
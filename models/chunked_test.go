@@ -0,0 +1,57 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAndLoadChunkProgressRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.json")
+	want := chunkProgress{URL: "https://example.com/update.msi", Size: 100, ChunkSize: 40, Done: []bool{true, false, true}}
+	if err := saveChunkProgress(path, want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := loadChunkProgress(path)
+	if got.URL != want.URL || got.Size != want.Size || got.ChunkSize != want.ChunkSize || len(got.Done) != len(want.Done) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want.Done {
+		if got.Done[i] != want.Done[i] {
+			t.Errorf("Done[%d]: got %v, want %v", i, got.Done[i], want.Done[i])
+		}
+	}
+}
+
+func TestLoadChunkProgressMissingFile(t *testing.T) {
+	got := loadChunkProgress(filepath.Join(t.TempDir(), "missing.json"))
+	if got.URL != "" || got.Size != 0 || got.Done != nil {
+		t.Errorf("expected a zero-value chunkProgress for a missing file, got %+v", got)
+	}
+}
+
+func TestLoadChunkProgressCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := loadChunkProgress(path)
+	if got.URL != "" || got.Size != 0 || got.Done != nil {
+		t.Errorf("expected a zero-value chunkProgress for a corrupt file, got %+v", got)
+	}
+}
+
+func TestAllocateSparseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "part")
+	if err := allocateSparseFile(path, 1024); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Size() != 1024 {
+		t.Errorf("got size %d, want 1024", info.Size())
+	}
+}
@@ -0,0 +1,243 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"go.viam.com/rdk/logging"
+)
+
+// objectMetadata is backend-agnostic change-detection state for the remote
+// object a Fetcher points at.
+type objectMetadata struct {
+	ContentLength int64
+	// Version is an opaque token that changes whenever the remote object
+	// does: an HTTP ETag, an S3 ETag/VersionId, a GCS generation, or an
+	// "mtime:size" composite for file:// sources.
+	Version string
+}
+
+// Fetcher downloads the configured update from a specific storage backend.
+type Fetcher interface {
+	// Metadata returns change-detection metadata for the configured remote
+	// object without downloading it.
+	Metadata(ctx context.Context) (objectMetadata, error)
+	// Fetch downloads the object into destDir, verifying it against
+	// expectedSHA256 (hex-encoded) when non-empty, and returns the path to the
+	// downloaded file. The checksum is computed by re-reading the finished
+	// file, not while the bytes are transferred.
+	Fetch(ctx context.Context, destDir string, expectedSHA256 string) (string, error)
+}
+
+// newFetcher returns the Fetcher appropriate for cfg.DownloadURL's scheme.
+func newFetcher(cfg *Config, logger logging.Logger) (Fetcher, error) {
+	u, err := url.Parse(cfg.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid download_url %q: %w", cfg.DownloadURL, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "":
+		return &httpFetcher{
+			url:                    cfg.DownloadURL,
+			logger:                 logger,
+			maxParallelChunks:      cfg.MaxParallelChunks,
+			chunkSize:              cfg.ChunkSize,
+			maxConcurrentDownloads: cfg.MaxConcurrentDownloads,
+		}, nil
+	case "file":
+		return &fileFetcher{path: filePathFromURL(u)}, nil
+	case "s3":
+		return newS3Fetcher(cfg, u)
+	case "gs":
+		return newGCSFetcher(cfg, u)
+	case "azblob":
+		return newAzureBlobFetcher(cfg, u)
+	default:
+		return nil, fmt.Errorf("unsupported download_url scheme %q", u.Scheme)
+	}
+}
+
+// filePathFromURL converts a file:// URL into a local filesystem path,
+// accounting for Windows drive-letter hosts/paths (file:///C:/path or
+// file://C:/path).
+func filePathFromURL(u *url.URL) string {
+	p := u.Path
+	if u.Host != "" && u.Host != "localhost" {
+		return filepath.FromSlash(u.Host + p)
+	}
+	p = filepath.FromSlash(p)
+	for len(p) > 0 && p[0] == filepath.Separator && len(p) > 2 && p[2] == ':' {
+		p = p[1:]
+	}
+	return p
+}
+
+// httpFetcher downloads over http(s). When the server advertises
+// Accept-Ranges: bytes, it issues up to maxParallelChunks concurrent Range
+// requests into a sparse file and persists per-chunk progress so an
+// interrupted download resumes on the next Fetch instead of restarting; if
+// ranges aren't supported, it falls back to a single sequential GET. Every
+// whole-file download acquires a permit from the module-level download pool
+// shared across all updater instances on this machine before it starts.
+type httpFetcher struct {
+	url    string
+	logger logging.Logger
+
+	maxParallelChunks      int
+	chunkSize              int64
+	maxConcurrentDownloads int
+}
+
+func (f *httpFetcher) Metadata(ctx context.Context) (objectMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, f.url, nil)
+	if err != nil {
+		return objectMetadata{}, fmt.Errorf("could not build HEAD request for %s: %w", f.url, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return objectMetadata{}, fmt.Errorf("could not get metadata for %s: %w", f.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return objectMetadata{}, fmt.Errorf("could not get metadata for %s: status %s", f.url, resp.Status)
+	}
+	return objectMetadata{ContentLength: resp.ContentLength, Version: resp.Header.Get("etag")}, nil
+}
+
+func (f *httpFetcher) Fetch(ctx context.Context, destDir string, expectedSHA256 string) (string, error) {
+	pool := getDownloadPool(f.maxConcurrentDownloads)
+	if err := pool.Acquire(ctx, 1); err != nil {
+		return "", fmt.Errorf("could not acquire a download pool slot: %w", err)
+	}
+	defer pool.Release(1)
+
+	dest := filepath.Join(destDir, filenameFromURL(f.url))
+
+	size, supportsRanges, err := f.probeRangeSupport(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if supportsRanges && size > 0 {
+		if err := f.fetchChunked(ctx, dest, size); err != nil {
+			return "", err
+		}
+	} else {
+		f.logger.Debugf("server for %s does not support range requests, falling back to a sequential download", f.url)
+		if err := f.fetchSequential(ctx, dest); err != nil {
+			return "", err
+		}
+	}
+
+	if expectedSHA256 != "" {
+		if err := verifyFileSHA256(dest, expectedSHA256); err != nil {
+			os.Remove(dest)
+			return "", err
+		}
+	}
+	return dest, nil
+}
+
+func filenameFromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "download"
+	}
+	base := path.Base(u.Path)
+	if base == "" || base == "/" || base == "." {
+		return "download"
+	}
+	return base
+}
+
+func (f *httpFetcher) probeRangeSupport(ctx context.Context) (size int64, supportsRanges bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, f.url, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("could not build HEAD request for %s: %w", f.url, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("could not reach %s: %w", f.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("could not reach %s: status %s", f.url, resp.Status)
+	}
+	return resp.ContentLength, resp.Header.Get("Accept-Ranges") == "bytes", nil
+}
+
+func (f *httpFetcher) fetchSequential(ctx context.Context, dest string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return fmt.Errorf("could not build request for %s: %w", f.url, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not download %s: %w", f.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not download %s: status %s", f.url, resp.Status)
+	}
+	return writeFile(dest, resp.Body)
+}
+
+// fileFetcher copies a pre-staged installer from a local or UNC share into
+// the destination directory, for file:// download_url values.
+type fileFetcher struct {
+	path string
+}
+
+func (f *fileFetcher) Metadata(ctx context.Context) (objectMetadata, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return objectMetadata{}, fmt.Errorf("could not stat %s: %w", f.path, err)
+	}
+	return objectMetadata{
+		ContentLength: info.Size(),
+		Version:       fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size()),
+	}, nil
+}
+
+func (f *fileFetcher) Fetch(ctx context.Context, destDir string, expectedSHA256 string) (string, error) {
+	dest := filepath.Join(destDir, filepath.Base(f.path))
+	if err := copyFile(f.path, dest); err != nil {
+		return "", fmt.Errorf("could not copy %s to %s: %w", f.path, dest, err)
+	}
+	if expectedSHA256 != "" {
+		if err := verifyFileSHA256(dest, expectedSHA256); err != nil {
+			os.Remove(dest)
+			return "", err
+		}
+	}
+	return dest, nil
+}
+
+// verifyFileSHA256 is used by every fetcher to check a download: it hashes
+// the downloaded file after the transfer has finished.
+func verifyFileSHA256(path string, expectedSHA256 string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open %s to verify checksum: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("could not hash %s: %w", path, err)
+	}
+	got := hex.EncodeToString(h.Sum(nil))
+	if got != expectedSHA256 {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", path, expectedSHA256, got)
+	}
+	return nil
+}
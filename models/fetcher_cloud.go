@@ -0,0 +1,235 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	azblob "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"google.golang.org/api/option"
+)
+
+// s3Fetcher downloads from s3://bucket/key, using S3's ETag/VersionId for
+// change detection.
+type s3Fetcher struct {
+	client *s3.Client
+	bucket string
+	key    string
+}
+
+func newS3Fetcher(cfg *Config, u *url.URL) (*s3Fetcher, error) {
+	var optFns []func(*awsconfig.LoadOptions) error
+	if cfg.S3Region != "" {
+		optFns = append(optFns, awsconfig.WithRegion(cfg.S3Region))
+	}
+	if cfg.S3AccessKeyID != "" || cfg.S3SecretAccessKey != "" {
+		optFns = append(optFns, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.S3AccessKeyID, cfg.S3SecretAccessKey, "")))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("could not load AWS config: %w", err)
+	}
+	return &s3Fetcher{
+		client: s3.NewFromConfig(awsCfg),
+		bucket: u.Host,
+		key:    strings.TrimPrefix(u.Path, "/"),
+	}, nil
+}
+
+func (f *s3Fetcher) Metadata(ctx context.Context) (objectMetadata, error) {
+	out, err := f.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &f.bucket, Key: &f.key})
+	if err != nil {
+		return objectMetadata{}, fmt.Errorf("could not head s3://%s/%s: %w", f.bucket, f.key, err)
+	}
+	version := ""
+	if out.ETag != nil {
+		version = *out.ETag
+	}
+	if out.VersionId != nil {
+		version += ":" + *out.VersionId
+	}
+	length := int64(0)
+	if out.ContentLength != nil {
+		length = *out.ContentLength
+	}
+	return objectMetadata{ContentLength: length, Version: version}, nil
+}
+
+func (f *s3Fetcher) Fetch(ctx context.Context, destDir string, expectedSHA256 string) (string, error) {
+	out, err := f.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &f.bucket, Key: &f.key})
+	if err != nil {
+		return "", fmt.Errorf("could not get s3://%s/%s: %w", f.bucket, f.key, err)
+	}
+	defer out.Body.Close()
+
+	dest := filepath.Join(destDir, filepath.Base(f.key))
+	if err := writeFile(dest, out.Body); err != nil {
+		return "", err
+	}
+	if expectedSHA256 != "" {
+		if err := verifyFileSHA256(dest, expectedSHA256); err != nil {
+			os.Remove(dest)
+			return "", err
+		}
+	}
+	return dest, nil
+}
+
+// gcsFetcher downloads from gs://bucket/object, using GCS's generation for
+// change detection.
+type gcsFetcher struct {
+	client *storage.Client
+	bucket string
+	object string
+}
+
+func newGCSFetcher(cfg *Config, u *url.URL) (*gcsFetcher, error) {
+	var opts []option.ClientOption
+	if cfg.GCSCredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(cfg.GCSCredentialsFile))
+	}
+	client, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create GCS client: %w", err)
+	}
+	return &gcsFetcher{
+		client: client,
+		bucket: u.Host,
+		object: strings.TrimPrefix(u.Path, "/"),
+	}, nil
+}
+
+func (f *gcsFetcher) handle() *storage.ObjectHandle {
+	return f.client.Bucket(f.bucket).Object(f.object)
+}
+
+func (f *gcsFetcher) Metadata(ctx context.Context) (objectMetadata, error) {
+	attrs, err := f.handle().Attrs(ctx)
+	if err != nil {
+		return objectMetadata{}, fmt.Errorf("could not get attrs for gs://%s/%s: %w", f.bucket, f.object, err)
+	}
+	return objectMetadata{
+		ContentLength: attrs.Size,
+		Version:       fmt.Sprintf("%d", attrs.Generation),
+	}, nil
+}
+
+func (f *gcsFetcher) Fetch(ctx context.Context, destDir string, expectedSHA256 string) (string, error) {
+	r, err := f.handle().NewReader(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not read gs://%s/%s: %w", f.bucket, f.object, err)
+	}
+	defer r.Close()
+
+	dest := filepath.Join(destDir, filepath.Base(f.object))
+	if err := writeFile(dest, r); err != nil {
+		return "", err
+	}
+	if expectedSHA256 != "" {
+		if err := verifyFileSHA256(dest, expectedSHA256); err != nil {
+			os.Remove(dest)
+			return "", err
+		}
+	}
+	return dest, nil
+}
+
+// azureBlobFetcher downloads from azblob://container/blob, using Azure's
+// ETag for change detection.
+type azureBlobFetcher struct {
+	client    *azblob.Client
+	container string
+	blob      string
+}
+
+func newAzureBlobFetcher(cfg *Config, u *url.URL) (*azureBlobFetcher, error) {
+	if cfg.AzureStorageAccount == "" {
+		return nil, fmt.Errorf("azure_storage_account is required for azblob:// download_url values")
+	}
+	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", cfg.AzureStorageAccount)
+
+	var client *azblob.Client
+	var err error
+	if cfg.AzureStorageKey != "" {
+		cred, credErr := azblob.NewSharedKeyCredential(cfg.AzureStorageAccount, cfg.AzureStorageKey)
+		if credErr != nil {
+			return nil, fmt.Errorf("invalid azure_storage_key: %w", credErr)
+		}
+		client, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+	} else {
+		var cred azcore.TokenCredential
+		cred, err = azidentity.NewDefaultAzureCredential(nil)
+		if err == nil {
+			client, err = azblob.NewClient(serviceURL, cred, nil)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create Azure Blob client: %w", err)
+	}
+
+	return &azureBlobFetcher{
+		client:    client,
+		container: u.Host,
+		blob:      strings.TrimPrefix(u.Path, "/"),
+	}, nil
+}
+
+func (f *azureBlobFetcher) Metadata(ctx context.Context) (objectMetadata, error) {
+	props, err := f.client.ServiceClient().NewContainerClient(f.container).NewBlobClient(f.blob).GetProperties(ctx, nil)
+	if err != nil {
+		return objectMetadata{}, fmt.Errorf("could not get properties for azblob://%s/%s: %w", f.container, f.blob, err)
+	}
+	version := ""
+	if props.ETag != nil {
+		version = string(*props.ETag)
+	}
+	length := int64(0)
+	if props.ContentLength != nil {
+		length = *props.ContentLength
+	}
+	return objectMetadata{ContentLength: length, Version: version}, nil
+}
+
+func (f *azureBlobFetcher) Fetch(ctx context.Context, destDir string, expectedSHA256 string) (string, error) {
+	dest := filepath.Join(destDir, filepath.Base(f.blob))
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", fmt.Errorf("could not create %s: %w", dest, err)
+	}
+	defer out.Close()
+
+	_, err = f.client.DownloadFile(ctx, f.container, f.blob, out, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not download azblob://%s/%s: %w", f.container, f.blob, err)
+	}
+	if expectedSHA256 != "" {
+		if err := verifyFileSHA256(dest, expectedSHA256); err != nil {
+			os.Remove(dest)
+			return "", err
+		}
+	}
+	return dest, nil
+}
+
+func writeFile(dest string, r io.Reader) error {
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", dest, err)
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, r); err != nil {
+		return fmt.Errorf("could not write %s: %w", dest, err)
+	}
+	return nil
+}
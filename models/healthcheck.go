@@ -0,0 +1,177 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+	"unsafe"
+
+	"go.viam.com/utils"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const defaultHealthCheckTimeout = 30 * time.Second
+
+func (s *windowsAutoupdateUpdater) healthCheckTimeout() time.Duration {
+	if s.cfg.HealthCheckTimeout == "" {
+		return defaultHealthCheckTimeout
+	}
+	d, err := time.ParseDuration(s.cfg.HealthCheckTimeout)
+	if err != nil {
+		return defaultHealthCheckTimeout
+	}
+	return d
+}
+
+// runHealthCheck runs the configured HealthCheck, retrying until it passes or
+// HealthCheckTimeout elapses.
+func (s *windowsAutoupdateUpdater) runHealthCheck(ctx context.Context) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, s.healthCheckTimeout())
+	defer cancel()
+
+	check := s.cfg.HealthCheck
+	var lastErr error
+	for utils.SelectContextOrWait(timeoutCtx, 2*time.Second) {
+		switch {
+		case check.HTTP != nil:
+			lastErr = checkHTTP(timeoutCtx, check.HTTP)
+		case check.Process != nil:
+			lastErr = checkProcess(check.Process)
+		case check.Command != nil:
+			lastErr = checkCommand(timeoutCtx, check.Command)
+		default:
+			return fmt.Errorf("health_check did not specify http, process, or command")
+		}
+		if lastErr == nil {
+			s.logger.Infof("post-install health check passed")
+			return nil
+		}
+		s.logger.Debugf("health check not yet passing: %v", lastErr)
+	}
+	if lastErr == nil {
+		lastErr = timeoutCtx.Err()
+	}
+	return fmt.Errorf("health check did not pass within %s: %w", s.healthCheckTimeout(), lastErr)
+}
+
+func checkHTTP(ctx context.Context, check *HTTPHealthCheck) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, check.URL, nil)
+	if err != nil {
+		return fmt.Errorf("could not build request for %s: %w", check.URL, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not reach %s: %w", check.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if check.ExpectedStatus != 0 && resp.StatusCode != check.ExpectedStatus {
+		return fmt.Errorf("expected status %d from %s, got %d", check.ExpectedStatus, check.URL, resp.StatusCode)
+	}
+	if check.BodyRegex == "" {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("could not read response body from %s: %w", check.URL, err)
+	}
+	matched, err := regexp.Match(check.BodyRegex, body)
+	if err != nil {
+		return fmt.Errorf("invalid body_regex %q: %w", check.BodyRegex, err)
+	}
+	if !matched {
+		return fmt.Errorf("response body from %s did not match %q", check.URL, check.BodyRegex)
+	}
+	return nil
+}
+
+func checkProcess(check *ProcessHealthCheck) error {
+	if check.ServiceName != "" {
+		return checkServiceRunning(check.ServiceName)
+	}
+	if check.ProcessImageName != "" {
+		return checkProcessRunning(check.ProcessImageName)
+	}
+	return fmt.Errorf("process health check did not specify service_name or process_image_name")
+}
+
+func checkServiceRunning(serviceName string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("could not connect to service control manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	service, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("could not open service %s: %w", serviceName, err)
+	}
+	defer service.Close()
+
+	status, err := service.Query()
+	if err != nil {
+		return fmt.Errorf("could not query service %s: %w", serviceName, err)
+	}
+	if status.State != svc.Running {
+		return fmt.Errorf("service %s is not running (state %d)", serviceName, status.State)
+	}
+	return nil
+}
+
+// checkProcessRunning reports an error unless a process named imageName
+// (e.g. "myapp.exe") is present in the system's process list.
+func checkProcessRunning(imageName string) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return fmt.Errorf("could not snapshot running processes: %w", err)
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	if err := windows.Process32First(snapshot, &entry); err != nil {
+		return fmt.Errorf("could not read process list: %w", err)
+	}
+	for {
+		name := windows.UTF16ToString(entry.ExeFile[:])
+		if strings.EqualFold(name, imageName) {
+			return nil
+		}
+		if err := windows.Process32Next(snapshot, &entry); err != nil {
+			break
+		}
+	}
+	return fmt.Errorf("no running process found with image name %s", imageName)
+}
+
+func checkCommand(ctx context.Context, check *CommandHealthCheck) error {
+	cmd := exec.CommandContext(ctx, check.Command, check.Args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("health check command failed: %s: %w", string(output), err)
+	}
+	return nil
+}
+
+// handleFailedHealthCheck rolls back a failed install if rollback history is
+// available, or otherwise marks the cache entry unininstalled so the next
+// tick retries the install.
+func (s *windowsAutoupdateUpdater) handleFailedHealthCheck(ctx context.Context, healthErr error) (map[string]interface{}, error) {
+	if _, err := s.rollback(ctx); err != nil {
+		s.logger.Errorf("automatic rollback after failed health check also failed: %v", err)
+		cacheDetails := s.getCacheDetails()
+		cacheDetails.Installed = false
+		if err := s.setCacheDetails(cacheDetails); err != nil {
+			s.logger.Errorf("error setting cache details: %v", err)
+		}
+		return nil, fmt.Errorf("health check failed (%w) and rollback also failed (%v)", healthErr, err)
+	}
+	return nil, fmt.Errorf("health check failed, rolled back to previous version: %w", healthErr)
+}
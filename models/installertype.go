@@ -0,0 +1,183 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+type installerType string
+
+const (
+	installerTypeAuto          installerType = "auto"
+	installerTypeMSI           installerType = "msi"
+	installerTypeNSIS          installerType = "exe-nsis"
+	installerTypeInno          installerType = "exe-inno"
+	installerTypeInstallShield installerType = "exe-installshield"
+	installerTypeBat           installerType = "bat"
+)
+
+var validInstallerTypes = []installerType{
+	installerTypeAuto, installerTypeMSI, installerTypeNSIS, installerTypeInno, installerTypeInstallShield, installerTypeBat,
+}
+
+// msiMagic is the OLE/Compound File Binary Format signature every .msi
+// (itself a compound file) starts with.
+var msiMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// resolveInstallerType returns the configured InstallerType, or sniffs
+// installer if it is unset or "auto".
+func (s *windowsAutoupdateUpdater) resolveInstallerType(installer string) (installerType, error) {
+	if s.cfg.InstallerType != "" && installerType(s.cfg.InstallerType) != installerTypeAuto {
+		return installerType(s.cfg.InstallerType), nil
+	}
+	return sniffInstallerType(installer)
+}
+
+// sniffInstallerType inspects installer's magic bytes and, for PE binaries,
+// well-known installer-framework resource strings, to guess its type.
+func sniffInstallerType(installer string) (installerType, error) {
+	if strings.EqualFold(filepath.Ext(installer), ".bat") {
+		return installerTypeBat, nil
+	}
+
+	f, err := os.Open(installer)
+	if err != nil {
+		return "", fmt.Errorf("could not open %s: %w", installer, err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 8)
+	if _, err := f.Read(header); err != nil {
+		return "", fmt.Errorf("could not read %s: %w", installer, err)
+	}
+	if bytes.Equal(header, msiMagic) {
+		return installerTypeMSI, nil
+	}
+
+	// Not an MSI; if it's a PE (MZ) binary, look for known installer
+	// framework markers among its embedded strings.
+	if header[0] == 'M' && header[1] == 'Z' {
+		info, err := f.Stat()
+		if err != nil {
+			return "", fmt.Errorf("could not stat %s: %w", installer, err)
+		}
+		limit := info.Size()
+		if limit > 8<<20 {
+			limit = 8 << 20 // looking at the first few MB is enough to find these markers
+		}
+		if _, err := f.Seek(0, 0); err != nil {
+			return "", err
+		}
+		buf := make([]byte, limit)
+		if _, err := io.ReadFull(f, buf); err != nil && err != io.ErrUnexpectedEOF {
+			return "", fmt.Errorf("could not read %s: %w", installer, err)
+		}
+		switch {
+		case bytes.Contains(buf, []byte("Nullsoft")):
+			return installerTypeNSIS, nil
+		case bytes.Contains(buf, []byte("Inno Setup")):
+			return installerTypeInno, nil
+		case bytes.Contains(buf, []byte("InstallShield")):
+			return installerTypeInstallShield, nil
+		}
+	}
+
+	return "", fmt.Errorf("could not determine installer type for %s; set installer_type explicitly", installer)
+}
+
+// buildInstallCommand returns the program and arguments used to silently
+// install installer, plus the MSI verbose log path when applicable.
+func buildInstallCommand(t installerType, installer string, extraArgs []string) (name string, args []string, logPath string, err error) {
+	switch t {
+	case installerTypeMSI:
+		logPath = installer + ".install.log"
+		return "msiexec", append([]string{"/i", installer, "/qn", "/l*v", logPath}, extraArgs...), logPath, nil
+	case installerTypeNSIS:
+		return installer, append([]string{"/S"}, extraArgs...), "", nil
+	case installerTypeInno:
+		return installer, append([]string{"/VERYSILENT", "/SUPPRESSMSGBOX", "/NORESTART"}, extraArgs...), "", nil
+	case installerTypeInstallShield:
+		return installer, append([]string{"/s", "/v/qn"}, extraArgs...), "", nil
+	case installerTypeBat:
+		return "cmd", append([]string{"/C", installer}, extraArgs...), "", nil
+	default:
+		return "", nil, "", fmt.Errorf("unsupported installer_type %q", t)
+	}
+}
+
+// uninstallByProductCode runs msiexec /x against an MSI ProductCode directly,
+// skipping a registry scan.
+func uninstallByProductCode(productCode string) error {
+	cmd := exec.Command("msiexec", "/x", productCode, "/qn")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("encountered error uninstalling product %s: %s", productCode, string(output))
+	}
+	return nil
+}
+
+// Windows Installer API (msi.dll) bindings used to read an MSI's ProductCode
+// property directly off disk, mirroring the WinVerifyTrust bindings in
+// verify.go.
+var (
+	modMsi                   = windows.NewLazySystemDLL("msi.dll")
+	procMsiOpenDatabaseW     = modMsi.NewProc("MsiOpenDatabaseW")
+	procMsiDatabaseOpenViewW = modMsi.NewProc("MsiDatabaseOpenViewW")
+	procMsiViewExecute       = modMsi.NewProc("MsiViewExecute")
+	procMsiViewFetch         = modMsi.NewProc("MsiViewFetch")
+	procMsiRecordGetStringW  = modMsi.NewProc("MsiRecordGetStringW")
+	procMsiCloseHandle       = modMsi.NewProc("MsiCloseHandle")
+)
+
+const msiDBOpenReadOnly = 0
+
+// msiProductCode reads the ProductCode property out of an MSI's Property
+// table via the Windows Installer API.
+func msiProductCode(installer string) (string, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(installer)
+	if err != nil {
+		return "", fmt.Errorf("could not convert path %q: %w", installer, err)
+	}
+
+	var db uintptr
+	if ret, _, _ := procMsiOpenDatabaseW.Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(msiDBOpenReadOnly), uintptr(unsafe.Pointer(&db))); ret != 0 {
+		return "", fmt.Errorf("MsiOpenDatabaseW failed with code %d", ret)
+	}
+	defer procMsiCloseHandle.Call(db)
+
+	queryPtr, err := syscall.UTF16PtrFromString("SELECT `Value` FROM `Property` WHERE `Property`='ProductCode'")
+	if err != nil {
+		return "", err
+	}
+	var view uintptr
+	if ret, _, _ := procMsiDatabaseOpenViewW.Call(db, uintptr(unsafe.Pointer(queryPtr)), uintptr(unsafe.Pointer(&view))); ret != 0 {
+		return "", fmt.Errorf("MsiDatabaseOpenViewW failed with code %d", ret)
+	}
+	defer procMsiCloseHandle.Call(view)
+
+	if ret, _, _ := procMsiViewExecute.Call(view, 0); ret != 0 {
+		return "", fmt.Errorf("MsiViewExecute failed with code %d", ret)
+	}
+
+	var record uintptr
+	if ret, _, _ := procMsiViewFetch.Call(view, uintptr(unsafe.Pointer(&record))); ret != 0 {
+		return "", fmt.Errorf("could not find ProductCode property: MsiViewFetch returned %d", ret)
+	}
+	defer procMsiCloseHandle.Call(record)
+
+	buf := make([]uint16, 64)
+	size := uint32(len(buf))
+	if ret, _, _ := procMsiRecordGetStringW.Call(record, 1, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size))); ret != 0 {
+		return "", fmt.Errorf("MsiRecordGetStringW failed with code %d", ret)
+	}
+	return syscall.UTF16ToString(buf[:size]), nil
+}
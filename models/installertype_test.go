@@ -0,0 +1,86 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeInstallerFixture(t *testing.T, name string, contents []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, contents, 0644); err != nil {
+		t.Fatalf("could not write fixture %s: %v", path, err)
+	}
+	return path
+}
+
+func TestSniffInstallerTypeBatExtension(t *testing.T) {
+	path := writeInstallerFixture(t, "install.bat", []byte("@echo off\n"))
+	got, err := sniffInstallerType(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != installerTypeBat {
+		t.Errorf("got %q, want %q", got, installerTypeBat)
+	}
+}
+
+func TestSniffInstallerTypeMSIMagic(t *testing.T) {
+	contents := append(append([]byte{}, msiMagic...), make([]byte, 24)...)
+	path := writeInstallerFixture(t, "update.msi", contents)
+	got, err := sniffInstallerType(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != installerTypeMSI {
+		t.Errorf("got %q, want %q", got, installerTypeMSI)
+	}
+}
+
+func peFixture(marker string) []byte {
+	header := []byte("MZ")
+	header = append(header, make([]byte, 64)...)
+	header = append(header, []byte(marker)...)
+	return header
+}
+
+func TestSniffInstallerTypeNSISMarker(t *testing.T) {
+	path := writeInstallerFixture(t, "setup.exe", peFixture("Nullsoft Install System"))
+	got, err := sniffInstallerType(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != installerTypeNSIS {
+		t.Errorf("got %q, want %q", got, installerTypeNSIS)
+	}
+}
+
+func TestSniffInstallerTypeInnoMarker(t *testing.T) {
+	path := writeInstallerFixture(t, "setup.exe", peFixture("Inno Setup"))
+	got, err := sniffInstallerType(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != installerTypeInno {
+		t.Errorf("got %q, want %q", got, installerTypeInno)
+	}
+}
+
+func TestSniffInstallerTypeInstallShieldMarker(t *testing.T) {
+	path := writeInstallerFixture(t, "setup.exe", peFixture("InstallShield"))
+	got, err := sniffInstallerType(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != installerTypeInstallShield {
+		t.Errorf("got %q, want %q", got, installerTypeInstallShield)
+	}
+}
+
+func TestSniffInstallerTypeUnknown(t *testing.T) {
+	path := writeInstallerFixture(t, "setup.exe", peFixture("nothing recognizable here"))
+	if _, err := sniffInstallerType(path); err == nil {
+		t.Error("expected an error for a PE binary with no known installer-framework marker")
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
@@ -17,7 +16,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/cavaliergopher/grab/v3"
 	"go.viam.com/rdk/components/generic"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/resource"
@@ -48,6 +46,113 @@ type Config struct {
 	RegistryLookupValue    string   `json:"registry_lookup_value"`
 	AbortOnUninstallErrors bool     `json:"abort_on_uninstall_errors"`
 	ForceInstall           bool     `json:"force_install"`
+
+	// SHA256 is the expected hex-encoded checksum of the downloaded file. Takes
+	// precedence over SHA256URL if both are set.
+	SHA256 string `json:"sha256"`
+	// SHA256URL points to a checksum file (e.g. SHA256SUMS) whose contents are
+	// fetched and parsed for the expected digest of the downloaded file.
+	SHA256URL string `json:"sha256_url"`
+	// SignaturePublicKey is a hex-encoded Ed25519 public key used to verify the
+	// detached signature (minisign-style) over the checksum file fetched from
+	// SHA256URL.
+	SignaturePublicKey string `json:"signature_public_key"`
+	// SignatureURL points to the detached signature over the checksum file.
+	SignatureURL string `json:"signature_url"`
+	// RequireAuthenticode, when true, requires .exe/.msi installers to carry a
+	// valid Authenticode signature (verified via WinVerifyTrust) before install.
+	RequireAuthenticode bool `json:"require_authenticode"`
+	// AuthenticodeSubject, if set, additionally requires the signer's subject
+	// name to contain this string.
+	AuthenticodeSubject string `json:"authenticode_subject"`
+
+	// RollbackHistoryDepth is how many previous installer versions are
+	// retained for rollback. Defaults to 3.
+	RollbackHistoryDepth int `json:"rollback_history_depth"`
+
+	// HealthCheck, if set, is run after installUpdate returns and must pass
+	// before the install is considered successful.
+	HealthCheck *HealthCheck `json:"health_check"`
+	// HealthCheckTimeout bounds how long HealthCheck is given to pass, as a
+	// Go duration string (e.g. "30s"). Defaults to 30s.
+	HealthCheckTimeout string `json:"health_check_timeout"`
+
+	// MaintenanceWindows restricts uninstall/install to the given windows,
+	// each formatted as "<days> <start>-<end> <timezone>", e.g.
+	// "Mon-Fri 22:00-23:59 America/Los_Angeles". If empty, installs are
+	// allowed at any time.
+	MaintenanceWindows []string `json:"maintenance_windows"`
+	// RolloutJitter is a Go duration string; each host delays its own install
+	// eligibility by a stable offset within this range, seeded by hostname,
+	// to avoid a fleet-wide stampede at the start of a window.
+	RolloutJitter string `json:"rollout_jitter"`
+	// MaxInstallsPerWindow caps how many installs this instance will perform
+	// per calendar day. Zero means unlimited.
+	MaxInstallsPerWindow int `json:"max_installs_per_window"`
+
+	// S3Region, S3AccessKeyID, and S3SecretAccessKey configure the fetcher
+	// used for s3:// download_url values. If credentials are left empty, the
+	// AWS SDK's default credential chain (including an EC2/ECS instance
+	// profile) is used instead.
+	S3Region          string `json:"s3_region"`
+	S3AccessKeyID     string `json:"s3_access_key_id"`
+	S3SecretAccessKey string `json:"s3_secret_access_key"`
+
+	// GCSCredentialsFile points to a service account JSON key file used for
+	// gs:// download_url values. If empty, Application Default Credentials
+	// are used instead.
+	GCSCredentialsFile string `json:"gcs_credentials_file"`
+
+	// AzureStorageAccount and AzureStorageKey configure the fetcher used for
+	// azblob:// download_url values.
+	AzureStorageAccount string `json:"azure_storage_account"`
+	AzureStorageKey     string `json:"azure_storage_key"`
+
+	// MaxParallelChunks is how many Range requests an http(s) download issues
+	// concurrently. Defaults to 4. Only used when the server advertises
+	// Accept-Ranges: bytes; otherwise the download falls back to sequential.
+	MaxParallelChunks int `json:"max_parallel_chunks"`
+	// ChunkSize is the size, in bytes, of each Range request. Defaults to 8MB.
+	ChunkSize int64 `json:"chunk_size"`
+	// MaxConcurrentDownloads bounds the module-level worker pool shared by
+	// every windowsAutoupdateUpdater configured on this machine, so many
+	// updater components don't saturate the NIC at once. Defaults to 2; the
+	// first configured value wins for the lifetime of the process.
+	MaxConcurrentDownloads int `json:"max_concurrent_downloads"`
+
+	// InstallerType selects how installer is invoked: "auto" (sniff the
+	// file), "msi", "exe-nsis", "exe-inno", "exe-installshield", or "bat".
+	// Defaults to "auto".
+	InstallerType string `json:"installer_type"`
+}
+
+// HealthCheck describes how to confirm an installed update is actually
+// working. Exactly one of HTTP, Process, or Command should be set.
+type HealthCheck struct {
+	HTTP    *HTTPHealthCheck    `json:"http,omitempty"`
+	Process *ProcessHealthCheck `json:"process,omitempty"`
+	Command *CommandHealthCheck `json:"command,omitempty"`
+}
+
+// HTTPHealthCheck probes a URL and requires ExpectedStatus (if nonzero) and a
+// body match against BodyRegex (if set).
+type HTTPHealthCheck struct {
+	URL            string `json:"url"`
+	ExpectedStatus int    `json:"expected_status"`
+	BodyRegex      string `json:"body_regex"`
+}
+
+// ProcessHealthCheck requires either a running service (looked up via the
+// Windows SCM) or a running process with a matching image name.
+type ProcessHealthCheck struct {
+	ServiceName      string `json:"service_name"`
+	ProcessImageName string `json:"process_image_name"`
+}
+
+// CommandHealthCheck requires Command to exit 0.
+type CommandHealthCheck struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
 }
 
 func (cfg *Config) Validate(path string) ([]string, error) {
@@ -55,6 +160,45 @@ func (cfg *Config) Validate(path string) ([]string, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid address '%s' for component at path '%s': %w", cfg.DownloadURL, path, err)
 	}
+	if (cfg.SignaturePublicKey == "") != (cfg.SignatureURL == "") {
+		return nil, fmt.Errorf("signature_public_key and signature_url must be set together for component at path '%s'", path)
+	}
+	if cfg.SignatureURL != "" && cfg.SHA256URL == "" {
+		return nil, fmt.Errorf("signature_url requires sha256_url for component at path '%s', since the signature is verified over the fetched checksum file", path)
+	}
+	if cfg.HealthCheck != nil {
+		set := 0
+		if cfg.HealthCheck.HTTP != nil {
+			set++
+		}
+		if cfg.HealthCheck.Process != nil {
+			set++
+		}
+		if cfg.HealthCheck.Command != nil {
+			set++
+		}
+		if set != 1 {
+			return nil, fmt.Errorf("health_check for component at path '%s' must set exactly one of http, process, or command", path)
+		}
+		if cfg.HealthCheckTimeout != "" {
+			if _, err := time.ParseDuration(cfg.HealthCheckTimeout); err != nil {
+				return nil, fmt.Errorf("invalid health_check_timeout '%s' for component at path '%s': %w", cfg.HealthCheckTimeout, path, err)
+			}
+		}
+	}
+	for _, window := range cfg.MaintenanceWindows {
+		if _, err := parseMaintenanceWindow(window); err != nil {
+			return nil, fmt.Errorf("invalid maintenance_windows entry for component at path '%s': %w", path, err)
+		}
+	}
+	if cfg.RolloutJitter != "" {
+		if _, err := time.ParseDuration(cfg.RolloutJitter); err != nil {
+			return nil, fmt.Errorf("invalid rollout_jitter '%s' for component at path '%s': %w", cfg.RolloutJitter, path, err)
+		}
+	}
+	if cfg.InstallerType != "" && !slices.Contains(validInstallerTypes, installerType(cfg.InstallerType)) {
+		return nil, fmt.Errorf("invalid installer_type '%s' for component at path '%s'", cfg.InstallerType, path)
+	}
 	return nil, nil
 }
 
@@ -73,8 +217,18 @@ type windowsAutoupdateUpdater struct {
 type cacheDetails struct {
 	DownloadURL   string `json:"download_url"`
 	ContentLength int64  `json:"content_length"`
-	ETag          string `json:"etag"`
-	Installed     bool   `json:"installed"`
+	// Version is the backend-specific change-detection token for the remote
+	// object: an HTTP ETag, an S3 ETag/VersionId, a GCS generation, or an
+	// "mtime:size" composite for file:// sources.
+	Version   string `json:"version"`
+	Installed bool   `json:"installed"`
+
+	// InstallerType and ProductCode record how the currently installed
+	// update was installed, so a later uninstall (e.g. before the next
+	// update, or during rollback) can target it directly: msiexec /x
+	// {ProductCode} for MSIs, instead of scanning the uninstall registry.
+	InstallerType string `json:"installer_type,omitempty"`
+	ProductCode   string `json:"product_code,omitempty"`
 }
 
 func newWindowsAutoupdateUpdater(ctx context.Context, deps resource.Dependencies, rawConf resource.Config, logger logging.Logger) (resource.Resource, error) {
@@ -107,7 +261,16 @@ func (s *windowsAutoupdateUpdater) downloadIgnoringReturn(ctx context.Context) {
 }
 
 func (s *windowsAutoupdateUpdater) downloadUpdate(ctx context.Context) (string, error) {
-	if !s.updateHasChanged(ctx) {
+	fetcher, err := newFetcher(s.cfg, s.logger)
+	if err != nil {
+		return "", fmt.Errorf("could not set up download backend for %s: %w", s.cfg.DownloadURL, err)
+	}
+
+	meta, err := fetcher.Metadata(ctx)
+	if err != nil {
+		s.logger.Errorf("error getting metadata for %s: %v", s.cfg.DownloadURL, err)
+	}
+	if !s.updateHasChanged(meta) {
 		s.logger.Infof("no update needed")
 		return "", errNoUpdateNeeded
 	}
@@ -123,59 +286,57 @@ func (s *windowsAutoupdateUpdater) downloadUpdate(ctx context.Context) (string,
 		}
 	}
 
-	client := grab.NewClient()
-	req, err := grab.NewRequest(destination, s.cfg.DownloadURL)
+	if meta.ContentLength > 0 {
+		if freeSpace, err := getFreeDiskSpace(destination[:2]); err == nil {
+			if freeSpace < uint64(meta.ContentLength*3) {
+				return "", fmt.Errorf("not enough free space on drive %s: %d bytes available, %d bytes needed", destination[:2], freeSpace, meta.ContentLength*3)
+			}
+		}
+	}
+
+	expectedSum, checksumBlob, err := s.expectedChecksum(ctx)
 	if err != nil {
-		return "", fmt.Errorf("could not create request: %w", err)
+		return "", fmt.Errorf("could not determine expected checksum: %w", err)
 	}
-	req = req.WithContext(ctx)
 
 	// start download
-	s.logger.Infof("downloading update from: %v", req.URL())
-	resp := client.Do(req)
-
-	if freeSpace, err := getFreeDiskSpace(destination[:2]); err == nil {
-		if freeSpace < uint64(resp.Size()*3) {
-			resp.Cancel()
-			return "", fmt.Errorf("not enough free space on drive %s: %d bytes available, %d bytes needed", destination[:2], freeSpace, resp.Size()*3)
-		}
+	s.logger.Infof("downloading update from: %v", s.cfg.DownloadURL)
+	filename, err := fetcher.Fetch(ctx, destination, expectedSum)
+	if err != nil {
+		return "", fmt.Errorf("could not download file: %w", err)
 	}
 
-	// start status loop
-	t := time.NewTicker(1 * time.Second)
-	defer t.Stop()
-
-Loop:
-	for {
-		select {
-		case <-t.C:
-			s.logger.Debugf("downloaded %v / %v bytes (%.2f%%)", resp.BytesComplete(), resp.Size(), 100*resp.Progress())
-		case <-resp.Done:
-			s.logger.Debugf("downloaded %v / %v bytes (%.2f%%)", resp.BytesComplete(), resp.Size(), 100*resp.Progress())
-			break Loop
+	if s.cfg.SignatureURL != "" {
+		if err := s.verifyChecksumSignature(ctx, checksumBlob); err != nil {
+			os.Remove(filename)
+			return "", fmt.Errorf("signature verification failed, refusing to install: %w", err)
 		}
 	}
 
-	// check for errors
-	if err := resp.Err(); err != nil {
-		return "", fmt.Errorf("could not download file: %w", err)
+	if s.cfg.RequireAuthenticode {
+		if ext := strings.ToLower(path.Ext(filename)); ext == ".exe" || ext == ".msi" {
+			if err := verifyAuthenticode(filename, s.cfg.AuthenticodeSubject); err != nil {
+				os.Remove(filename)
+				return "", fmt.Errorf("authenticode verification failed, refusing to install: %w", err)
+			}
+		}
 	}
 
 	// save download details
 	cacheDetails := cacheDetails{
 		DownloadURL:   s.cfg.DownloadURL,
-		ContentLength: resp.HTTPResponse.ContentLength,
-		ETag:          resp.HTTPResponse.Header.Get("etag"),
+		ContentLength: meta.ContentLength,
+		Version:       meta.Version,
 		Installed:     false,
 	}
 	s.setCacheDetails(cacheDetails)
 
 	// success
-	s.logger.Infof("update saved to %s", resp.Filename)
-	return resp.Filename, nil
+	s.logger.Infof("update saved to %s", filename)
+	return filename, nil
 }
 
-func (s *windowsAutoupdateUpdater) updateHasChanged(ctx context.Context) bool {
+func (s *windowsAutoupdateUpdater) updateHasChanged(meta objectMetadata) bool {
 	if s.cfg.ForceInstall {
 		return true
 	}
@@ -185,17 +346,12 @@ func (s *windowsAutoupdateUpdater) updateHasChanged(ctx context.Context) bool {
 		s.logger.Debugf("download URL has changed from %s to %s", cacheDetails.DownloadURL, s.cfg.DownloadURL)
 		return true
 	}
-	resp, err := http.Head(s.cfg.DownloadURL)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		s.logger.Errorf("error getting head for %s: %v", s.cfg.DownloadURL, err)
+	if meta.ContentLength != cacheDetails.ContentLength {
+		s.logger.Debugf("content length has changed from %d to %d", cacheDetails.ContentLength, meta.ContentLength)
 		return true
 	}
-	if resp.ContentLength != cacheDetails.ContentLength {
-		s.logger.Debugf("content length has changed from %d to %d", cacheDetails.ContentLength, resp.ContentLength)
-		return true
-	}
-	if resp.Header.Get("etag") != cacheDetails.ETag {
-		s.logger.Debugf("etag has changed from %s to %s", cacheDetails.ETag, resp.Header.Get("etag"))
+	if meta.Version != cacheDetails.Version {
+		s.logger.Debugf("version has changed from %s to %s", cacheDetails.Version, meta.Version)
 		return true
 	}
 	if !cacheDetails.Installed {
@@ -382,6 +538,13 @@ func (s *windowsAutoupdateUpdater) findInstaller(src string) (string, string, er
 }
 
 func (s *windowsAutoupdateUpdater) uninstallExistingInstallation() error {
+	// If the last install was an MSI, we already know its ProductCode and can
+	// target it directly, without a registry scan.
+	if cached := s.getCacheDetails(); cached.ProductCode != "" {
+		s.logger.Infof("uninstalling previous MSI by product code %s", cached.ProductCode)
+		return uninstallByProductCode(cached.ProductCode)
+	}
+
 	// Skip uninstall step if these config values are not provided
 	if len(strings.TrimSpace(s.cfg.RegistryLookupKey)) <= 0 {
 		s.logger.Info("Skipping uninstall: Registry lookup key was not provided.")
@@ -467,20 +630,53 @@ func (s *windowsAutoupdateUpdater) uninstallExistingInstallation() error {
 	return nil
 }
 
-func (s *windowsAutoupdateUpdater) installUpdate(installer string) error {
-	s.logger.Infof("installing update from %s", installer)
-	args := append([]string{"/C", installer}, s.cfg.InstallArgs...)
-	cmd := exec.Command("cmd", args...)
-	s.logger.Infof("installation command: %s", args)
+// installResult carries diagnostics about how installer was installed, for
+// DoCommand to surface back to the caller and to persist into cacheDetails.
+type installResult struct {
+	InstallerType string
+	ProductCode   string
+	LogPath       string
+}
+
+func (s *windowsAutoupdateUpdater) installUpdate(installer string) (installResult, error) {
+	return s.installUpdateWithArgs(installer, s.cfg.InstallArgs)
+}
+
+func (s *windowsAutoupdateUpdater) installUpdateWithArgs(installer string, extraArgs []string) (installResult, error) {
+	t, err := s.resolveInstallerType(installer)
+	if err != nil {
+		return installResult{}, fmt.Errorf("could not determine installer type for %s: %w", installer, err)
+	}
+
+	var productCode string
+	if t == installerTypeMSI {
+		productCode, err = msiProductCode(installer)
+		if err != nil {
+			s.logger.Errorf("could not read MSI product code from %s: %v", installer, err)
+		}
+	}
+
+	name, args, logPath, err := buildInstallCommand(t, installer, extraArgs)
+	if err != nil {
+		return installResult{}, err
+	}
+
+	s.logger.Infof("installing update from %s as %s: %s %s", installer, t, name, args)
+	cmd := exec.Command(name, args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("encountered error installing program: %s", string(output[:]))
+		return installResult{InstallerType: string(t), ProductCode: productCode, LogPath: logPath},
+			fmt.Errorf("encountered error installing program: %s", string(output[:]))
 	}
 	s.logger.Infof("successfully installed: %s", string(output[:]))
-	return nil
+	return installResult{InstallerType: string(t), ProductCode: productCode, LogPath: logPath}, nil
 }
 
 func (s *windowsAutoupdateUpdater) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	if command, ok := cmd["command"].(string); ok && command == "rollback" {
+		return s.rollback(ctx)
+	}
+
 	for utils.SelectContextOrWait(ctx, 1*time.Second) {
 		if s.downloadComplete {
 			break
@@ -506,22 +702,77 @@ func (s *windowsAutoupdateUpdater) DoCommand(ctx context.Context, cmd map[string
 		}
 	}()
 
+	// An installer extracted from a .zip was not covered by the Authenticode
+	// check in downloadUpdate, which only saw the archive.
+	if s.cfg.RequireAuthenticode && dir != "" {
+		if ext := strings.ToLower(filepath.Ext(installer)); ext == ".exe" || ext == ".msi" {
+			if err := verifyAuthenticode(installer, s.cfg.AuthenticodeSubject); err != nil {
+				return nil, fmt.Errorf("authenticode verification failed, refusing to install: %w", err)
+			}
+		}
+	}
+
+	if err := s.snapshotForRollback(); err != nil {
+		s.logger.Errorf("could not snapshot current installation for rollback: %v", err)
+	}
+
+	if ok, next := s.withinMaintenanceWindow(time.Now()); !ok {
+		s.logger.Infof("deferring install: outside maintenance window, next eligible at %s", next)
+		return nil, fmt.Errorf("deferring install: outside maintenance window, next eligible at %s", next)
+	}
+	if ok, err := s.recordInstallInWindow(time.Now()); err != nil {
+		s.logger.Errorf("error recording install in window: %v", err)
+	} else if !ok {
+		s.logger.Infof("deferring install: max_installs_per_window already reached for today")
+		return nil, fmt.Errorf("deferring install: max_installs_per_window already reached for today")
+	}
+
 	if err := s.uninstallExistingInstallation(); err != nil && s.cfg.AbortOnUninstallErrors {
 		return nil, err
 	}
 
-	if err := s.installUpdate(installer); err != nil {
+	result, err := s.installUpdate(installer)
+	if err != nil {
 		return nil, err
 	}
 
-	// Update cache details to indicate that the update has been installed
+	// Record how this (not yet health-checked) install was installed right
+	// away, so that if the health check below fails, uninstallExistingInstallation
+	// targets the version we just installed by ProductCode instead of the
+	// stale entry for the version it replaced.
 	cacheDetails := s.getCacheDetails()
+	cacheDetails.InstallerType = result.InstallerType
+	cacheDetails.ProductCode = result.ProductCode
+	if err := s.setCacheDetails(cacheDetails); err != nil {
+		s.logger.Errorf("error setting cache details: %v", err)
+	}
+
+	if s.cfg.HealthCheck != nil {
+		if err := s.runHealthCheck(ctx); err != nil {
+			s.logger.Errorf("post-install health check failed: %v", err)
+			return s.handleFailedHealthCheck(ctx, err)
+		}
+	}
+
+	if err := s.retainActiveInstaller(installer); err != nil {
+		s.logger.Errorf("could not retain installer for future rollback: %v", err)
+	}
+
+	// Update cache details to indicate that the update has been installed
+	cacheDetails = s.getCacheDetails()
 	cacheDetails.Installed = true
 	if err := s.setCacheDetails(cacheDetails); err != nil {
 		s.logger.Errorf("error setting cache details: %v", err)
 	}
 
-	return nil, nil
+	response := map[string]interface{}{"installer_type": result.InstallerType}
+	if result.ProductCode != "" {
+		response["product_code"] = result.ProductCode
+	}
+	if result.LogPath != "" {
+		response["log_path"] = result.LogPath
+	}
+	return response, nil
 }
 
 func (s *windowsAutoupdateUpdater) Close(context.Context) error {
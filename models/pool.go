@@ -0,0 +1,30 @@
+package models
+
+import (
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+const defaultMaxConcurrentDownloads = 2
+
+var (
+	downloadPoolOnce sync.Once
+	downloadPool     *semaphore.Weighted
+)
+
+// getDownloadPool returns the process-wide download worker pool, shared by
+// every windowsAutoupdateUpdater configured on this machine so a robot with
+// many updater components doesn't saturate the NIC by downloading several
+// updates at once. Its capacity is fixed by whichever configured
+// max_concurrent_downloads is observed first; capacity <= 0 falls back to
+// defaultMaxConcurrentDownloads.
+func getDownloadPool(capacity int) *semaphore.Weighted {
+	downloadPoolOnce.Do(func() {
+		if capacity <= 0 {
+			capacity = defaultMaxConcurrentDownloads
+		}
+		downloadPool = semaphore.NewWeighted(int64(capacity))
+	})
+	return downloadPool
+}
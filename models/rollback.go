@@ -0,0 +1,338 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const defaultRollbackHistoryDepth = 3
+
+// rollbackEntry captures enough state about an installed version to reinstall
+// it later, mirroring what uninstallExistingInstallation reads out of the
+// uninstall registry key.
+type rollbackEntry struct {
+	Timestamp       string   `json:"timestamp"`
+	UninstallString string   `json:"uninstall_string"`
+	InstallerPath   string   `json:"installer_path"`
+	InstallArgs     []string `json:"install_args"`
+}
+
+type rollbackHistory struct {
+	Entries []rollbackEntry `json:"entries"`
+}
+
+// activeInstaller records a retained copy of the installer behind the
+// currently installed (and, if health-checked, healthy) version, so the next
+// snapshotForRollback call has something to retain that isn't the brand-new
+// download about to replace it.
+type activeInstaller struct {
+	Path        string   `json:"path"`
+	InstallArgs []string `json:"install_args"`
+}
+
+func (s *windowsAutoupdateUpdater) getRollbackDir() (string, error) {
+	cacheDir, err := s.getCacheDir()
+	if err != nil {
+		return "", err
+	}
+	rollbackDir := filepath.Join(cacheDir, "rollback")
+	if err := os.MkdirAll(rollbackDir, 0755); err != nil {
+		return "", err
+	}
+	return rollbackDir, nil
+}
+
+func (s *windowsAutoupdateUpdater) getRollbackHistoryFile() (string, error) {
+	rollbackDir, err := s.getRollbackDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(rollbackDir, "rollback.json"), nil
+}
+
+func (s *windowsAutoupdateUpdater) getRollbackHistory() rollbackHistory {
+	historyFile, err := s.getRollbackHistoryFile()
+	if err != nil {
+		return rollbackHistory{}
+	}
+	f, err := os.Open(historyFile)
+	if err != nil {
+		return rollbackHistory{}
+	}
+	defer f.Close()
+	var history rollbackHistory
+	if err := json.NewDecoder(f).Decode(&history); err != nil {
+		return rollbackHistory{}
+	}
+	return history
+}
+
+func (s *windowsAutoupdateUpdater) setRollbackHistory(history rollbackHistory) error {
+	historyFile, err := s.getRollbackHistoryFile()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(historyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(history)
+}
+
+func (s *windowsAutoupdateUpdater) rollbackHistoryDepth() int {
+	if s.cfg.RollbackHistoryDepth > 0 {
+		return s.cfg.RollbackHistoryDepth
+	}
+	return defaultRollbackHistoryDepth
+}
+
+func (s *windowsAutoupdateUpdater) getActiveInstallerFile() (string, error) {
+	rollbackDir, err := s.getRollbackDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(rollbackDir, "active.json"), nil
+}
+
+// getActiveInstaller returns the retained installer behind the version
+// currently installed, if retainActiveInstaller has ever been called.
+func (s *windowsAutoupdateUpdater) getActiveInstaller() (activeInstaller, bool) {
+	activeFile, err := s.getActiveInstallerFile()
+	if err != nil {
+		return activeInstaller{}, false
+	}
+	f, err := os.Open(activeFile)
+	if err != nil {
+		return activeInstaller{}, false
+	}
+	defer f.Close()
+	var active activeInstaller
+	if err := json.NewDecoder(f).Decode(&active); err != nil {
+		return activeInstaller{}, false
+	}
+	if active.Path == "" {
+		return activeInstaller{}, false
+	}
+	return active, true
+}
+
+// retainActiveInstaller copies installer into the rollback directory and
+// records it as the currently active version, so the next snapshotForRollback
+// call has a previous-version installer to retain that isn't whatever the
+// in-flight download just wrote into the cache/download directory. Must be
+// called only once installer is confirmed installed (and healthy, if a
+// health check is configured), before it is cleaned up by the caller.
+func (s *windowsAutoupdateUpdater) retainActiveInstaller(installer string) error {
+	previous, hadPrevious := s.getActiveInstaller()
+
+	rollbackDir, err := s.getRollbackDir()
+	if err != nil {
+		return err
+	}
+	retained := filepath.Join(rollbackDir, "active"+filepath.Ext(installer))
+	if err := copyFile(installer, retained); err != nil {
+		return fmt.Errorf("could not retain active installer: %w", err)
+	}
+
+	activeFile, err := s.getActiveInstallerFile()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(activeFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(activeInstaller{Path: retained, InstallArgs: s.cfg.InstallArgs}); err != nil {
+		return err
+	}
+
+	if hadPrevious && previous.Path != retained {
+		os.Remove(previous.Path)
+	}
+	return nil
+}
+
+// snapshotForRollback records the currently installed program's uninstall
+// metadata and retains a copy of the currently active installer (recorded by
+// the prior call to retainActiveInstaller) so a later rollback can reinstall
+// the version being replaced now.
+func (s *windowsAutoupdateUpdater) snapshotForRollback() error {
+	entry := rollbackEntry{
+		Timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+	}
+
+	uninstallString, err := s.lookupUninstallString()
+	if err != nil {
+		return fmt.Errorf("could not find current installation's uninstall command: %w", err)
+	}
+	entry.UninstallString = uninstallString
+
+	// Retain a copy of the active installer (the one backing the version
+	// about to be replaced), not anything from the live download
+	// destination, which by this point already holds the new update. If
+	// retainActiveInstaller has never run, there's nothing to roll back to
+	// yet.
+	active, ok := s.getActiveInstaller()
+	if !ok {
+		s.logger.Debugf("no previously retained installer to snapshot for rollback")
+	} else {
+		entry.InstallArgs = active.InstallArgs
+		rollbackDir, err := s.getRollbackDir()
+		if err != nil {
+			return err
+		}
+		retained := filepath.Join(rollbackDir, entry.Timestamp+filepath.Ext(active.Path))
+		if err := copyFile(active.Path, retained); err != nil {
+			return fmt.Errorf("could not retain previous installer: %w", err)
+		}
+		entry.InstallerPath = retained
+	}
+
+	history := s.getRollbackHistory()
+	history.Entries = append([]rollbackEntry{entry}, history.Entries...)
+	kept, stale := trimRollbackHistory(history.Entries, s.rollbackHistoryDepth())
+	history.Entries = kept
+	for _, entry := range stale {
+		if entry.InstallerPath != "" {
+			os.Remove(entry.InstallerPath)
+		}
+	}
+	return s.setRollbackHistory(history)
+}
+
+// trimRollbackHistory splits entries, newest first, into the depth entries to
+// keep and the remainder to discard, so their retained installers can be
+// cleaned up. A depth of 0 or less keeps everything.
+func trimRollbackHistory(entries []rollbackEntry, depth int) (kept, stale []rollbackEntry) {
+	if depth <= 0 || len(entries) <= depth {
+		return entries, nil
+	}
+	return entries[:depth], entries[depth:]
+}
+
+// lookupUninstallString scans the uninstall registry keys for the first
+// program matching RegistryLookupKey/RegistryLookupValue and returns its
+// (quiet, if available) uninstall command, without running it.
+func (s *windowsAutoupdateUpdater) lookupUninstallString() (string, error) {
+	if strings.TrimSpace(s.cfg.RegistryLookupKey) == "" || strings.TrimSpace(s.cfg.RegistryLookupValue) == "" {
+		return "", fmt.Errorf("registry_lookup_key and registry_lookup_value are not configured")
+	}
+
+	keys := []string{
+		`SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall`,
+		`SOFTWARE\WOW6432Node\Microsoft\Windows\CurrentVersion\Uninstall`,
+	}
+
+	for _, keyName := range keys {
+		k, err := registry.OpenKey(registry.LOCAL_MACHINE, keyName, registry.READ)
+		if err != nil {
+			continue
+		}
+		defer k.Close()
+
+		subkeys, err := k.ReadSubKeyNames(0)
+		if err != nil {
+			continue
+		}
+		for _, subkey := range subkeys {
+			sk, err := registry.OpenKey(registry.LOCAL_MACHINE, fmt.Sprintf(`%s\%s`, keyName, subkey), registry.READ)
+			if err != nil {
+				continue
+			}
+			defer sk.Close()
+
+			lookupValue, _, err := sk.GetStringValue(s.cfg.RegistryLookupKey)
+			if err != nil || lookupValue != s.cfg.RegistryLookupValue {
+				continue
+			}
+
+			script, _, err := sk.GetStringValue("QuietUninstallString")
+			if err != nil || len(strings.TrimSpace(script)) <= 0 {
+				script, _, err = sk.GetStringValue("UninstallString")
+				if err != nil {
+					return "", fmt.Errorf("could not find uninstall command: %w", err)
+				}
+			}
+			if strings.Contains(script, "MsiExec.exe") {
+				script += " /quiet"
+			}
+			return script, nil
+		}
+	}
+	return "", fmt.Errorf("no installed program found matching %s=%s", s.cfg.RegistryLookupKey, s.cfg.RegistryLookupValue)
+}
+
+// rollback uninstalls the currently installed version and reinstalls the most
+// recently retained previous installer.
+func (s *windowsAutoupdateUpdater) rollback(ctx context.Context) (map[string]interface{}, error) {
+	history := s.getRollbackHistory()
+	if len(history.Entries) == 0 {
+		return nil, fmt.Errorf("no rollback history available")
+	}
+	entry := history.Entries[0]
+	if entry.InstallerPath == "" {
+		return nil, fmt.Errorf("rollback entry from %s did not retain an installer to reinstall", entry.Timestamp)
+	}
+	if _, err := os.Stat(entry.InstallerPath); err != nil {
+		return nil, fmt.Errorf("retained installer %s is no longer available: %w", entry.InstallerPath, err)
+	}
+
+	s.logger.Infof("rolling back to installer retained from %s", entry.Timestamp)
+
+	if err := s.uninstallExistingInstallation(); err != nil && s.cfg.AbortOnUninstallErrors {
+		return nil, fmt.Errorf("could not uninstall current version before rollback: %w", err)
+	}
+
+	result, err := s.installUpdateWithArgs(entry.InstallerPath, entry.InstallArgs)
+	if err != nil {
+		return nil, fmt.Errorf("encountered error reinstalling previous version: %w", err)
+	}
+	s.logger.Infof("successfully rolled back to %s", entry.Timestamp)
+
+	if err := s.retainActiveInstaller(entry.InstallerPath); err != nil {
+		s.logger.Errorf("could not retain installer for future rollback: %v", err)
+	}
+
+	cacheDetails := s.getCacheDetails()
+	cacheDetails.Installed = true
+	cacheDetails.InstallerType = result.InstallerType
+	cacheDetails.ProductCode = result.ProductCode
+	if err := s.setCacheDetails(cacheDetails); err != nil {
+		s.logger.Errorf("error setting cache details: %v", err)
+	}
+
+	history.Entries = history.Entries[1:]
+	if err := s.setRollbackHistory(history); err != nil {
+		s.logger.Errorf("error updating rollback history: %v", err)
+	}
+
+	return map[string]interface{}{"rolled_back_to": entry.Timestamp}, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestTrimRollbackHistoryUnderDepth(t *testing.T) {
+	entries := []rollbackEntry{{Timestamp: "1"}, {Timestamp: "2"}}
+	kept, stale := trimRollbackHistory(entries, 3)
+	if len(kept) != 2 || len(stale) != 0 {
+		t.Fatalf("got kept=%v stale=%v, want all entries kept and none stale", kept, stale)
+	}
+}
+
+func TestTrimRollbackHistoryOverDepth(t *testing.T) {
+	entries := []rollbackEntry{{Timestamp: "1"}, {Timestamp: "2"}, {Timestamp: "3"}}
+	kept, stale := trimRollbackHistory(entries, 2)
+	if len(kept) != 2 || kept[0].Timestamp != "1" || kept[1].Timestamp != "2" {
+		t.Errorf("got kept=%v, want the 2 newest entries retained", kept)
+	}
+	if len(stale) != 1 || stale[0].Timestamp != "3" {
+		t.Errorf("got stale=%v, want the oldest entry discarded", stale)
+	}
+}
+
+func TestTrimRollbackHistoryZeroDepthKeepsEverything(t *testing.T) {
+	entries := []rollbackEntry{{Timestamp: "1"}, {Timestamp: "2"}}
+	kept, stale := trimRollbackHistory(entries, 0)
+	if len(kept) != 2 || len(stale) != 0 {
+		t.Errorf("got kept=%v stale=%v, want a non-positive depth to keep everything", kept, stale)
+	}
+}
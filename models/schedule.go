@@ -0,0 +1,252 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// maintenanceWindow is a parsed MaintenanceWindows entry: the days of the
+// week it applies to, the start/end offsets from local midnight, and the
+// timezone those offsets are relative to.
+type maintenanceWindow struct {
+	days  map[time.Weekday]bool
+	start time.Duration
+	end   time.Duration
+	loc   *time.Location
+}
+
+// parseMaintenanceWindow parses a "<days> <start>-<end> <timezone>" spec,
+// e.g. "Mon-Fri 22:00-23:59 America/Los_Angeles" or "Sat,Sun 01:00-05:00 UTC".
+func parseMaintenanceWindow(spec string) (maintenanceWindow, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 3 {
+		return maintenanceWindow{}, fmt.Errorf("maintenance window %q must be '<days> <start>-<end> <timezone>'", spec)
+	}
+	days, err := parseDays(fields[0])
+	if err != nil {
+		return maintenanceWindow{}, fmt.Errorf("maintenance window %q: %w", spec, err)
+	}
+	start, end, err := parseTimeRange(fields[1])
+	if err != nil {
+		return maintenanceWindow{}, fmt.Errorf("maintenance window %q: %w", spec, err)
+	}
+	loc, err := time.LoadLocation(fields[2])
+	if err != nil {
+		return maintenanceWindow{}, fmt.Errorf("maintenance window %q: unknown timezone %q: %w", spec, fields[2], err)
+	}
+	return maintenanceWindow{days: days, start: start, end: end, loc: loc}, nil
+}
+
+func parseDays(spec string) (map[time.Weekday]bool, error) {
+	days := map[time.Weekday]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		if !strings.Contains(part, "-") {
+			d, ok := weekdayNames[strings.ToLower(part)]
+			if !ok {
+				return nil, fmt.Errorf("unknown day %q", part)
+			}
+			days[d] = true
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		startDay, ok1 := weekdayNames[strings.ToLower(bounds[0])]
+		endDay, ok2 := weekdayNames[strings.ToLower(bounds[1])]
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("unknown day range %q", part)
+		}
+		for d := startDay; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == endDay {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseTimeRange(spec string) (time.Duration, time.Duration, error) {
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("time range %q must be '<start>-<end>'", spec)
+	}
+	start, err := parseClockTime(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClockTime(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+func parseClockTime(spec string) (time.Duration, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("clock time %q must be 'HH:MM'", spec)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid hour in %q: %w", spec, err)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid minute in %q: %w", spec, err)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// contains reports whether t falls within the window, once jitter has been
+// added to the window's start. If the configured start is after end (e.g.
+// "22:00-06:00"), the window is treated as wrapping past midnight: a day listed
+// in days is eligible from start through the following morning's end.
+func (w maintenanceWindow) contains(t time.Time, jitter time.Duration) bool {
+	local := t.In(w.loc)
+	sinceMidnight := time.Duration(local.Hour())*time.Hour +
+		time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+	start := w.start + jitter
+
+	if w.start <= w.end {
+		return w.days[local.Weekday()] && sinceMidnight >= start && sinceMidnight <= w.end
+	}
+
+	if w.days[local.Weekday()] && sinceMidnight >= start {
+		return true
+	}
+	previousDay := (local.Weekday() + 6) % 7
+	return w.days[previousDay] && sinceMidnight <= w.end
+}
+
+// rolloutJitterOffset returns a stable, per-host delay within RolloutJitter,
+// seeded by hostname so every run on this machine lands on the same offset.
+func (s *windowsAutoupdateUpdater) rolloutJitterOffset() time.Duration {
+	if s.cfg.RolloutJitter == "" {
+		return 0
+	}
+	jitter, err := time.ParseDuration(s.cfg.RolloutJitter)
+	if err != nil || jitter <= 0 {
+		return 0
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = s.name.Name
+	}
+	h := fnv.New64a()
+	h.Write([]byte(host))
+	return time.Duration(h.Sum64() % uint64(jitter))
+}
+
+// withinMaintenanceWindow reports whether now falls within an allowed
+// maintenance window (offset by this host's stable rollout jitter). If no
+// windows are configured, installs are always allowed. Otherwise, the second
+// return value is a best-effort estimate of the next eligible time.
+func (s *windowsAutoupdateUpdater) withinMaintenanceWindow(now time.Time) (bool, time.Time) {
+	if len(s.cfg.MaintenanceWindows) == 0 {
+		return true, now
+	}
+
+	jitter := s.rolloutJitterOffset()
+	var next time.Time
+	for _, spec := range s.cfg.MaintenanceWindows {
+		window, err := parseMaintenanceWindow(spec)
+		if err != nil {
+			s.logger.Errorf("ignoring invalid maintenance window %q: %v", spec, err)
+			continue
+		}
+		if window.contains(now, jitter) {
+			return true, now
+		}
+		for i := 0; i < 8; i++ {
+			local := now.In(window.loc).AddDate(0, 0, i)
+			candidate := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, window.loc).Add(window.start + jitter)
+			if candidate.Before(now) || !window.days[candidate.Weekday()] {
+				continue
+			}
+			if next.IsZero() || candidate.Before(next) {
+				next = candidate
+			}
+			break
+		}
+	}
+	if next.IsZero() {
+		next = now.Add(24 * time.Hour)
+	}
+	return false, next
+}
+
+// scheduleState tracks how many installs this instance has performed on the
+// calendar day named by WindowKey, persisted alongside cache.json so it
+// survives restarts.
+type scheduleState struct {
+	WindowKey string `json:"window_key"`
+	Count     int    `json:"count"`
+}
+
+func (s *windowsAutoupdateUpdater) getScheduleStateFile() (string, error) {
+	cacheDir, err := s.getCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "schedule.json"), nil
+}
+
+func (s *windowsAutoupdateUpdater) getScheduleState() scheduleState {
+	stateFile, err := s.getScheduleStateFile()
+	if err != nil {
+		return scheduleState{}
+	}
+	f, err := os.Open(stateFile)
+	if err != nil {
+		return scheduleState{}
+	}
+	defer f.Close()
+	var state scheduleState
+	if err := json.NewDecoder(f).Decode(&state); err != nil {
+		return scheduleState{}
+	}
+	return state
+}
+
+func (s *windowsAutoupdateUpdater) setScheduleState(state scheduleState) error {
+	stateFile, err := s.getScheduleStateFile()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(stateFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(state)
+}
+
+// recordInstallInWindow reports whether another install is allowed under
+// MaxInstallsPerWindow for today, recording it if so. A zero
+// MaxInstallsPerWindow means unlimited.
+func (s *windowsAutoupdateUpdater) recordInstallInWindow(now time.Time) (bool, error) {
+	if s.cfg.MaxInstallsPerWindow <= 0 {
+		return true, nil
+	}
+	key := now.Format("2006-01-02")
+	state := s.getScheduleState()
+	if state.WindowKey != key {
+		state = scheduleState{WindowKey: key}
+	}
+	if state.Count >= s.cfg.MaxInstallsPerWindow {
+		return false, nil
+	}
+	state.Count++
+	return true, s.setScheduleState(state)
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseClockTime(t *testing.T) {
+	d, err := parseClockTime("22:15")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := 22*time.Hour + 15*time.Minute; d != want {
+		t.Errorf("got %v, want %v", d, want)
+	}
+
+	if _, err := parseClockTime("22"); err == nil {
+		t.Error("expected an error for a clock time missing minutes")
+	}
+	if _, err := parseClockTime("aa:00"); err == nil {
+		t.Error("expected an error for a non-numeric hour")
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	start, end, err := parseTimeRange("22:00-23:30")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := 22 * time.Hour; start != want {
+		t.Errorf("start: got %v, want %v", start, want)
+	}
+	if want := 23*time.Hour + 30*time.Minute; end != want {
+		t.Errorf("end: got %v, want %v", end, want)
+	}
+
+	if _, _, err := parseTimeRange("22:00"); err == nil {
+		t.Error("expected an error for a range missing an end time")
+	}
+}
+
+func TestParseDaysSingleAndList(t *testing.T) {
+	days, err := parseDays("Mon,Wed,Fri")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, d := range []time.Weekday{time.Monday, time.Wednesday, time.Friday} {
+		if !days[d] {
+			t.Errorf("expected %v to be included", d)
+		}
+	}
+	for _, d := range []time.Weekday{time.Tuesday, time.Thursday, time.Sunday, time.Saturday} {
+		if days[d] {
+			t.Errorf("expected %v to not be included", d)
+		}
+	}
+}
+
+func TestParseDaysRange(t *testing.T) {
+	days, err := parseDays("Mon-Fri")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, d := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday} {
+		if !days[d] {
+			t.Errorf("expected %v to be included", d)
+		}
+	}
+	for _, d := range []time.Weekday{time.Saturday, time.Sunday} {
+		if days[d] {
+			t.Errorf("expected %v to not be included", d)
+		}
+	}
+}
+
+func TestParseDaysRangeWraparound(t *testing.T) {
+	days, err := parseDays("Sat-Mon")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, d := range []time.Weekday{time.Saturday, time.Sunday, time.Monday} {
+		if !days[d] {
+			t.Errorf("expected %v to be included", d)
+		}
+	}
+	for _, d := range []time.Weekday{time.Tuesday, time.Wednesday, time.Thursday, time.Friday} {
+		if days[d] {
+			t.Errorf("expected %v to not be included", d)
+		}
+	}
+}
+
+func TestParseDaysUnknownDay(t *testing.T) {
+	if _, err := parseDays("Notaday"); err == nil {
+		t.Error("expected an error for an unknown day")
+	}
+	if _, err := parseDays("Mon-Notaday"); err == nil {
+		t.Error("expected an error for an unknown day in a range")
+	}
+}
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	window, err := parseMaintenanceWindow("Mon-Fri 22:00-23:59 UTC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !window.days[time.Wednesday] {
+		t.Error("expected Wednesday to be included")
+	}
+	if window.loc != time.UTC {
+		t.Errorf("got %v, want UTC", window.loc)
+	}
+
+	if _, err := parseMaintenanceWindow("Mon-Fri 22:00-23:59"); err == nil {
+		t.Error("expected an error for a spec missing a timezone")
+	}
+	if _, err := parseMaintenanceWindow("Mon-Fri 22:00-23:59 Not/AZone"); err == nil {
+		t.Error("expected an error for an unknown timezone")
+	}
+}
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	window, err := parseMaintenanceWindow("Mon-Fri 22:00-23:59 UTC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inWindow := time.Date(2026, 7, 22, 22, 30, 0, 0, time.UTC) // a Wednesday
+	if !window.contains(inWindow, 0) {
+		t.Error("expected time within the window to be contained")
+	}
+
+	outsideHours := time.Date(2026, 7, 22, 12, 0, 0, 0, time.UTC)
+	if window.contains(outsideHours, 0) {
+		t.Error("expected time outside the window's hours to not be contained")
+	}
+
+	wrongDay := time.Date(2026, 7, 25, 22, 30, 0, 0, time.UTC) // a Saturday
+	if window.contains(wrongDay, 0) {
+		t.Error("expected time on a day outside the window to not be contained")
+	}
+
+	if window.contains(inWindow, 2*time.Hour) {
+		t.Error("expected jitter pushing the start past the time to exclude it")
+	}
+}
+
+func TestMaintenanceWindowContainsOvernightWraparound(t *testing.T) {
+	window, err := parseMaintenanceWindow("Mon-Fri 22:00-06:00 UTC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lateNight := time.Date(2026, 7, 22, 23, 0, 0, 0, time.UTC) // a Wednesday
+	if !window.contains(lateNight, 0) {
+		t.Error("expected late-night time before midnight to be contained")
+	}
+
+	earlyMorning := time.Date(2026, 7, 23, 2, 0, 0, 0, time.UTC) // the following Thursday
+	if !window.contains(earlyMorning, 0) {
+		t.Error("expected early-morning time after midnight to be contained")
+	}
+
+	midday := time.Date(2026, 7, 22, 12, 0, 0, 0, time.UTC)
+	if window.contains(midday, 0) {
+		t.Error("expected midday time to not be contained")
+	}
+
+	// Saturday is outside days, but the small hours after a Friday-night
+	// window still belong to that Friday window.
+	saturdayMorning := time.Date(2026, 7, 25, 2, 0, 0, 0, time.UTC)
+	if !window.contains(saturdayMorning, 0) {
+		t.Error("expected early Saturday morning to still be contained, carried over from Friday night")
+	}
+
+	sundayMorning := time.Date(2026, 7, 26, 2, 0, 0, 0, time.UTC)
+	if window.contains(sundayMorning, 0) {
+		t.Error("expected early Sunday morning to not be contained, since Saturday is outside days")
+	}
+}
@@ -0,0 +1,296 @@
+package models
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// expectedChecksum returns the hex-encoded SHA256 digest the downloaded file
+// must match, preferring an explicitly configured value over one fetched from
+// SHA256URL, along with the raw checksum file bytes whenever SHA256URL was
+// fetched (needed to verify a detached signature over it, even if SHA256 also
+// won out as the digest actually checked against the download). An empty sum
+// means no checksum is configured.
+func (s *windowsAutoupdateUpdater) expectedChecksum(ctx context.Context) (sum string, raw []byte, err error) {
+	if s.cfg.SHA256 != "" && s.cfg.SignatureURL == "" {
+		return strings.ToLower(strings.TrimSpace(s.cfg.SHA256)), nil, nil
+	}
+	if s.cfg.SHA256URL == "" {
+		return strings.ToLower(strings.TrimSpace(s.cfg.SHA256)), nil, nil
+	}
+
+	blob, err := s.fetchChecksumFile(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	fileSum, err := parseChecksumFile(blob, filepath.Base(s.cfg.DownloadURL))
+	if err != nil {
+		return "", nil, err
+	}
+	if s.cfg.SHA256 != "" {
+		return strings.ToLower(strings.TrimSpace(s.cfg.SHA256)), blob, nil
+	}
+	return fileSum, blob, nil
+}
+
+// fetchChecksumFile downloads the raw contents of SHA256URL. The same bytes
+// are reused later to verify the detached signature, if one is configured.
+func (s *windowsAutoupdateUpdater) fetchChecksumFile(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.SHA256URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not build request for %s: %w", s.cfg.SHA256URL, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch checksum file %s: %w", s.cfg.SHA256URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch checksum file %s: status %s", s.cfg.SHA256URL, resp.Status)
+	}
+	blob, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("could not read checksum file %s: %w", s.cfg.SHA256URL, err)
+	}
+	return blob, nil
+}
+
+// parseChecksumFile parses a SHA256SUMS-style file ("<hex digest>  <filename>"
+// per line) and returns the digest for filename. If the file has a single
+// line with no filename, that digest is used regardless of filename.
+func parseChecksumFile(blob []byte, filename string) (string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(string(blob)))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("checksum file was empty")
+	}
+	if len(lines) == 1 {
+		if fields := strings.Fields(lines[0]); len(fields) == 1 {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if strings.TrimPrefix(fields[len(fields)-1], "*") == filename {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("could not find a checksum for %q in checksum file", filename)
+}
+
+// verifyChecksumSignature verifies the detached Ed25519 signature fetched
+// from SignatureURL over checksumBlob, using SignaturePublicKey.
+func (s *windowsAutoupdateUpdater) verifyChecksumSignature(ctx context.Context, checksumBlob []byte) error {
+	pubKeyBytes, err := hex.DecodeString(strings.TrimSpace(s.cfg.SignaturePublicKey))
+	if err != nil {
+		return fmt.Errorf("signature_public_key is not valid hex: %w", err)
+	}
+	if len(pubKeyBytes) != ed25519.PublicKeySize {
+		return fmt.Errorf("signature_public_key must be %d bytes, got %d", ed25519.PublicKeySize, len(pubKeyBytes))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.SignatureURL, nil)
+	if err != nil {
+		return fmt.Errorf("could not build request for %s: %w", s.cfg.SignatureURL, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not fetch signature %s: %w", s.cfg.SignatureURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not fetch signature %s: status %s", s.cfg.SignatureURL, resp.Status)
+	}
+	sigHex, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return fmt.Errorf("could not read signature %s: %w", s.cfg.SignatureURL, err)
+	}
+	sig, err := hex.DecodeString(strings.TrimSpace(string(sigHex)))
+	if err != nil {
+		return fmt.Errorf("signature at %s is not valid hex: %w", s.cfg.SignatureURL, err)
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(pubKeyBytes), checksumBlob, sig) {
+		return fmt.Errorf("signature does not match checksum file contents")
+	}
+	s.logger.Infof("verified signature over checksum file from %s", s.cfg.SignatureURL)
+	return nil
+}
+
+// Windows Authenticode verification via WinVerifyTrust. golang.org/x/sys/windows
+// does not bind wintrust.dll, so the procedure and structs it expects are
+// declared here.
+var (
+	modWintrust                   = windows.NewLazySystemDLL("wintrust.dll")
+	procWinVerifyTrust            = modWintrust.NewProc("WinVerifyTrust")
+	wintrustActionGenericVerifyV2 = windows.GUID{
+		Data1: 0x00AAC56B, Data2: 0xCD44, Data3: 0x11D0,
+		Data4: [8]byte{0x8C, 0xC2, 0x00, 0xC0, 0x4F, 0xC2, 0x95, 0xEE},
+	}
+)
+
+const (
+	wtdUICone            = 2
+	wtdRevokeNone        = 0
+	wtdChoiceFile        = 1
+	wtdStateActionVerify = 1
+	wtdStateActionClose  = 2
+	wtdSaferFlag         = 0x100
+	trustEOk             = 0
+)
+
+type wintrustFileInfo struct {
+	cbStruct       uint32
+	pcwszFilePath  *uint16
+	hFile          windows.Handle
+	pgKnownSubject *windows.GUID
+}
+
+type wintrustData struct {
+	cbStruct            uint32
+	pPolicyCallbackData uintptr
+	pSIPClientData      uintptr
+	dwUIChoice          uint32
+	fdwRevocationChecks uint32
+	dwUnionChoice       uint32
+	pFile               uintptr
+	dwStateAction       uint32
+	hWVTStateData       windows.Handle
+	pwszURLReference    *uint16
+	dwProvFlags         uint32
+	dwUIContext         uint32
+}
+
+// Bindings used to walk the signer certificate chain WinVerifyTrust builds
+// during verification, so verifyAuthenticode can additionally check the
+// signer's subject name. CRYPT_PROVIDER_DATA and CRYPT_PROVIDER_SGNR are only
+// ever passed between these calls as opaque pointers, so they're not declared
+// here; cryptProviderCert declares just enough of CRYPT_PROVIDER_CERT to read
+// its embedded certificate context.
+var (
+	modCrypt32                         = windows.NewLazySystemDLL("crypt32.dll")
+	procCertGetNameStringW             = modCrypt32.NewProc("CertGetNameStringW")
+	procWTHelperProvDataFromStateData  = modWintrust.NewProc("WTHelperProvDataFromStateData")
+	procWTHelperGetProvSignerFromChain = modWintrust.NewProc("WTHelperGetProvSignerFromChain")
+	procWTHelperGetProvCertFromChain   = modWintrust.NewProc("WTHelperGetProvCertFromChain")
+)
+
+const certNameSimpleDisplayType = 4
+
+type cryptProviderCert struct {
+	cbStruct uint32
+	pCert    uintptr
+}
+
+// verifyAuthenticode verifies that path carries a valid, trusted Authenticode
+// signature via WinVerifyTrust. If wantSubject is non-empty, the signer's
+// certificate subject name (as returned by CertGetNameStringW's simple
+// display form) must also contain it.
+func verifyAuthenticode(path string, wantSubject string) error {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return fmt.Errorf("could not convert path %q: %w", path, err)
+	}
+
+	fileInfo := wintrustFileInfo{pcwszFilePath: pathPtr}
+	fileInfo.cbStruct = uint32(unsafe.Sizeof(fileInfo))
+
+	data := wintrustData{
+		dwUIChoice:          wtdUICone,
+		fdwRevocationChecks: wtdRevokeNone,
+		dwUnionChoice:       wtdChoiceFile,
+		pFile:               uintptr(unsafe.Pointer(&fileInfo)),
+		dwStateAction:       wtdStateActionVerify,
+		dwProvFlags:         wtdSaferFlag,
+	}
+	data.cbStruct = uint32(unsafe.Sizeof(data))
+
+	ret, _, _ := procWinVerifyTrust.Call(
+		uintptr(0), // HWND_NOHANDLE
+		uintptr(unsafe.Pointer(&wintrustActionGenericVerifyV2)),
+		uintptr(unsafe.Pointer(&data)),
+	)
+
+	// The signer's certificate chain is only reachable while hWVTStateData is
+	// still open, so the subject check has to happen before it's closed below.
+	var subjectErr error
+	if ret == trustEOk && wantSubject != "" {
+		subject, err := signerSubjectName(data.hWVTStateData)
+		if err != nil {
+			subjectErr = fmt.Errorf("could not read signer subject for %s: %w", path, err)
+		} else if !strings.Contains(subject, wantSubject) {
+			subjectErr = fmt.Errorf("%s is signed by %q, which does not contain required authenticode_subject %q", path, subject, wantSubject)
+		}
+	}
+
+	data.dwStateAction = wtdStateActionClose
+	procWinVerifyTrust.Call(
+		uintptr(0),
+		uintptr(unsafe.Pointer(&wintrustActionGenericVerifyV2)),
+		uintptr(unsafe.Pointer(&data)),
+	)
+
+	if ret != trustEOk {
+		return fmt.Errorf("%s is not signed by a trusted publisher (WinVerifyTrust returned 0x%x)", path, uint32(ret))
+	}
+	if subjectErr != nil {
+		return subjectErr
+	}
+	return nil
+}
+
+// signerSubjectName walks the certificate chain built by a successful
+// WinVerifyTrust call (via its still-open state handle) and returns the
+// leaf signer certificate's subject, in CertGetNameStringW's simple display
+// form.
+func signerSubjectName(state windows.Handle) (string, error) {
+	provData, _, _ := procWTHelperProvDataFromStateData.Call(uintptr(state))
+	if provData == 0 {
+		return "", fmt.Errorf("WTHelperProvDataFromStateData returned no provider data")
+	}
+	signer, _, _ := procWTHelperGetProvSignerFromChain.Call(provData, 0, 0, 0)
+	if signer == 0 {
+		return "", fmt.Errorf("WTHelperGetProvSignerFromChain found no signer")
+	}
+	certPtr, _, _ := procWTHelperGetProvCertFromChain.Call(signer, 0)
+	if certPtr == 0 {
+		return "", fmt.Errorf("WTHelperGetProvCertFromChain found no signer certificate")
+	}
+	cert := (*cryptProviderCert)(unsafe.Pointer(certPtr))
+	if cert.pCert == 0 {
+		return "", fmt.Errorf("signer certificate chain entry had no certificate context")
+	}
+
+	var buf [512]uint16
+	n, _, _ := procCertGetNameStringW.Call(
+		cert.pCert,
+		uintptr(certNameSimpleDisplayType),
+		0,
+		0,
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(len(buf)),
+	)
+	if n <= 1 {
+		return "", fmt.Errorf("CertGetNameStringW returned no subject name")
+	}
+	return syscall.UTF16ToString(buf[:n-1]), nil
+}
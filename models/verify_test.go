@@ -0,0 +1,62 @@
+package models
+
+import "testing"
+
+func TestParseChecksumFileSingleLine(t *testing.T) {
+	sum, err := parseChecksumFile([]byte("ABCDEF0123456789\n"), "update.msi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "abcdef0123456789"; sum != want {
+		t.Errorf("got %q, want %q", sum, want)
+	}
+}
+
+func TestParseChecksumFileMultiLine(t *testing.T) {
+	blob := []byte("111111111111111111111111111111  other.msi\n" +
+		"222222222222222222222222222222  update.msi\n")
+	sum, err := parseChecksumFile(blob, "update.msi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "222222222222222222222222222222"; sum != want {
+		t.Errorf("got %q, want %q", sum, want)
+	}
+}
+
+func TestParseChecksumFileMultiLineStarPrefix(t *testing.T) {
+	blob := []byte("111111111111111111111111111111 *other.msi\n" +
+		"333333333333333333333333333333 *update.msi\n")
+	sum, err := parseChecksumFile(blob, "update.msi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "333333333333333333333333333333"; sum != want {
+		t.Errorf("got %q, want %q", sum, want)
+	}
+}
+
+func TestParseChecksumFileMultiLineNoMatch(t *testing.T) {
+	blob := []byte("111111111111111111111111111111  other.msi\n" +
+		"222222222222222222222222222222  another.msi\n")
+	if _, err := parseChecksumFile(blob, "update.msi"); err == nil {
+		t.Fatal("expected an error for a filename not present in the checksum file")
+	}
+}
+
+func TestParseChecksumFileEmpty(t *testing.T) {
+	if _, err := parseChecksumFile([]byte("\n\n"), "update.msi"); err == nil {
+		t.Fatal("expected an error for an empty checksum file")
+	}
+}
+
+func TestParseChecksumFileIgnoresBlankLines(t *testing.T) {
+	blob := []byte("\n  \n444444444444444444444444444444  update.msi\n\n")
+	sum, err := parseChecksumFile(blob, "update.msi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "444444444444444444444444444444"; sum != want {
+		t.Errorf("got %q, want %q", sum, want)
+	}
+}